@@ -0,0 +1,82 @@
+package parsec
+
+import (
+	"fmt"
+	"strings"
+)
+
+// merge two parse errors that failed at the same position into a single
+// "expected one of ..." error, falling back to the second error verbatim
+// when either side isn't a ParseErr
+func mergeParseErr(err1, err2 error) error {
+	pe1, ok1 := err1.(ParseErr)
+	pe2, ok2 := err2.(ParseErr)
+
+	if ok1 == false || ok2 == false {
+		return err2
+	}
+
+	expected := unionExpected(pe1.Expected, pe2.Expected)
+
+	if len(expected) == 0 {
+		return pe2
+	}
+
+	pe2.Expected = expected
+	pe2.Reason = formatExpected(expected)
+
+	return pe2
+}
+
+// union two sets of expected descriptions, preserving first-seen order
+func unionExpected(a, b []string) []string {
+	seen := make(map[string]bool)
+	expected := []string{}
+
+	for _, list := range [][]string{a, b} {
+		for _, s := range list {
+			if seen[s] == false {
+				seen[s] = true
+				expected = append(expected, s)
+			}
+		}
+	}
+
+	return expected
+}
+
+// render a set of expected descriptions as a single reason string
+func formatExpected(expected []string) string {
+	if len(expected) == 1 {
+		return fmt.Sprintf("Expected %s", expected[0])
+	}
+
+	return fmt.Sprintf("Expected one of %s", strings.Join(expected, ", "))
+}
+
+// render a parse error as a caret-underlined excerpt of the offending source
+// line, in the style of go/parser's error output
+func FormatError(src string, err error) string {
+	pe, ok := err.(ParseErr)
+
+	if ok == false {
+		return err.Error()
+	}
+
+	lines := strings.Split(src, "\n")
+	lineIdx := pe.Line - 1
+
+	if lineIdx < 0 || lineIdx >= len(lines) {
+		return pe.Error()
+	}
+
+	col := pe.Column
+
+	if col < 1 {
+		col = 1
+	}
+
+	caret := strings.Repeat(" ", col-1) + "^"
+
+	return fmt.Sprintf("%s\n%s\n%s", pe.Error(), lines[lineIdx], caret)
+}
@@ -0,0 +1,231 @@
+package parsec
+
+// the kind of fixity an Operator describes
+type operatorKind int
+
+const (
+	prefixKind operatorKind = iota
+	postfixKind
+	infixLKind
+	infixRKind
+	infixNKind
+)
+
+// a single operator entry in a BuildExpressionParser precedence table
+type Operator struct {
+	kind operatorKind
+	op Parser
+	unary func(interface{}) interface{}
+	binary func(interface{}, interface{}) interface{}
+}
+
+// a prefix unary operator, e.g. negation
+func Prefix(op Parser, f func(interface{}) interface{}) Operator {
+	return Operator{kind: prefixKind, op: op, unary: f}
+}
+
+// a postfix unary operator, e.g. factorial
+func Postfix(op Parser, f func(interface{}) interface{}) Operator {
+	return Operator{kind: postfixKind, op: op, unary: f}
+}
+
+// a left-associative infix operator, e.g. a - b - c == (a - b) - c
+func InfixL(op Parser, f func(interface{}, interface{}) interface{}) Operator {
+	return Operator{kind: infixLKind, op: op, binary: f}
+}
+
+// a right-associative infix operator, e.g. a ^ b ^ c == a ^ (b ^ c)
+func InfixR(op Parser, f func(interface{}, interface{}) interface{}) Operator {
+	return Operator{kind: infixRKind, op: op, binary: f}
+}
+
+// a non-associative infix operator; chaining it (a == b == c) is a parse error
+func InfixN(op Parser, f func(interface{}, interface{}) interface{}) Operator {
+	return Operator{kind: infixNKind, op: op, binary: f}
+}
+
+// build an operator-precedence expression parser from a table of operators,
+// ordered from lowest to highest precedence, and a parser for the terms
+// (atoms) the operators combine
+//
+// this is the spirit of Parsec's buildExpressionParser: each row of the
+// table is a precedence level, and rows bind tighter the closer they are
+// to the end of the table
+func BuildExpressionParser(table [][]Operator, term Parser) Parser {
+	parser := term
+
+	// build from the tightest-binding row outward to the loosest
+	for i := len(table) - 1; i >= 0; i-- {
+		parser = buildLevel(table[i], parser)
+	}
+
+	return parser
+}
+
+// build the parser for a single precedence level, wrapping the parser for
+// the next tighter level
+func buildLevel(ops []Operator, next Parser) Parser {
+	var prefixOps, postfixOps, infixOps []Operator
+
+	for _, o := range ops {
+		switch o.kind {
+		case prefixKind:
+			prefixOps = append(prefixOps, o)
+		case postfixKind:
+			postfixOps = append(postfixOps, o)
+		default:
+			infixOps = append(infixOps, o)
+		}
+	}
+
+	return withInfix(withAffixes(next, prefixOps, postfixOps), infixOps)
+}
+
+// wrap a term parser so it also consumes any prefix/postfix operators at
+// this level, applying them innermost-first
+func withAffixes(term Parser, prefixOps, postfixOps []Operator) Parser {
+	if len(prefixOps) == 0 && len(postfixOps) == 0 {
+		return term
+	}
+
+	prefix := operatorChoice(prefixOps)
+	postfix := operatorChoice(postfixOps)
+
+	return func(st *ParseState) (interface{}, error) {
+		var prefixed []interface{}
+
+		if prefix != nil {
+			xs, err := Many(prefix)(st)
+
+			if err != nil {
+				return nil, err
+			}
+
+			prefixed = xs.([]interface{})
+		}
+
+		x, err := term(st)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// apply prefix operators from the innermost (last parsed) outward
+		for i := len(prefixed) - 1; i >= 0; i-- {
+			x = prefixed[i].(Operator).unary(x)
+		}
+
+		if postfix != nil {
+			xs, err := Many(postfix)(st)
+
+			if err != nil {
+				return nil, err
+			}
+
+			for _, fo := range xs.([]interface{}) {
+				x = fo.(Operator).unary(x)
+			}
+		}
+
+		return x, nil
+	}
+}
+
+// wrap a term parser so it also consumes infix operators at this level,
+// looping for left-associative rows, recursing for right-associative rows,
+// and rejecting a second, chained use of a non-associative operator
+func withInfix(term Parser, infixOps []Operator) Parser {
+	opParser := operatorChoice(infixOps)
+
+	if opParser == nil {
+		return term
+	}
+
+	var parseLevel Parser
+
+	parseLevel = func(st *ParseState) (interface{}, error) {
+		x, err := term(st)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for {
+			m := st.Mark()
+			v, err := opParser(st)
+
+			if err != nil {
+				st.Reset(m)
+				return x, nil
+			}
+
+			o := v.(Operator)
+
+			switch o.kind {
+			case infixRKind:
+				// the right side re-enters this level, consuming the rest
+				// of the right-associative chain in one recursive call
+				y, err := parseLevel(st)
+
+				if err != nil {
+					return nil, err
+				}
+
+				st.commit(m)
+				return o.binary(x, y), nil
+
+			case infixNKind:
+				y, err := term(st)
+
+				if err != nil {
+					return nil, err
+				}
+
+				x = o.binary(x, y)
+				st.commit(m)
+
+				// a second, chained use of a non-associative operator is ambiguous
+				chained := st.Mark()
+
+				if _, err := opParser(st); err == nil {
+					return nil, st.trap("Ambiguous use of non-associative operator")
+				}
+
+				st.Reset(chained)
+				return x, nil
+
+			default: // infixLKind
+				y, err := term(st)
+
+				if err != nil {
+					return nil, err
+				}
+
+				x = o.binary(x, y)
+				st.commit(m)
+			}
+		}
+	}
+
+	return parseLevel
+}
+
+// build a parser that matches any one of a set of operators, yielding the
+// matched Operator itself; returns nil if the set is empty
+func operatorChoice(ops []Operator) Parser {
+	if len(ops) == 0 {
+		return nil
+	}
+
+	wrap := func(o Operator) Parser {
+		return Bind_(o.op, Return(o))
+	}
+
+	choice := wrap(ops[0])
+
+	for _, o := range ops[1:] {
+		choice = Either(choice, wrap(o))
+	}
+
+	return choice
+}
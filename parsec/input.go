@@ -0,0 +1,141 @@
+package parsec
+
+import "io"
+
+// how many bytes to pull from the reader at a time
+const inputChunkSize = 4096
+
+// a buffered, checkpointed view over an io.Reader
+//
+// bytes are read from the underlying reader on demand and retained only as
+// long as some outstanding checkpoint might still need to rewind to them;
+// once the oldest live checkpoint advances past a byte, it's dropped
+type inputBuffer struct {
+	r io.Reader
+	buf []byte
+	origin int
+	eof bool
+	checkpoints map[int]int
+	nextToken int
+}
+
+// wrap a reader in a fresh input buffer
+func newInputBuffer(r io.Reader) *inputBuffer {
+	return &inputBuffer{
+		r: r,
+		checkpoints: make(map[int]int),
+	}
+}
+
+// pull another chunk from the reader into the buffer
+func (b *inputBuffer) fill() {
+	if b.eof {
+		return
+	}
+
+	chunk := make([]byte, inputChunkSize)
+	n, err := b.r.Read(chunk)
+
+	if n > 0 {
+		b.buf = append(b.buf, chunk[:n]...)
+	}
+
+	if err != nil {
+		b.eof = true
+	}
+}
+
+// return the byte at an absolute stream position, reading ahead as needed
+func (b *inputBuffer) at(pos int) (byte, bool) {
+	rel := pos - b.origin
+
+	for rel >= len(b.buf) && !b.eof {
+		b.fill()
+	}
+
+	if rel >= 0 && rel < len(b.buf) {
+		return b.buf[rel], true
+	}
+
+	return 0, false
+}
+
+// return up to n buffered bytes starting at an absolute stream position
+func (b *inputBuffer) slice(pos, n int) []byte {
+	rel := pos - b.origin
+
+	for rel+n > len(b.buf) && !b.eof {
+		b.fill()
+	}
+
+	end := rel + n
+
+	if end > len(b.buf) {
+		end = len(b.buf)
+	}
+
+	if rel < 0 || rel >= end {
+		return nil
+	}
+
+	return b.buf[rel:end]
+}
+
+// register a checkpoint at a stream position, returning a token to release it
+func (b *inputBuffer) checkpoint(pos int) int {
+	token := b.nextToken
+	b.nextToken++
+	b.checkpoints[token] = pos
+
+	return token
+}
+
+// release a checkpoint, dropping any buffered bytes no longer reachable
+func (b *inputBuffer) release(token int, floor int) {
+	delete(b.checkpoints, token)
+
+	for _, pos := range b.checkpoints {
+		if pos < floor {
+			floor = pos
+		}
+	}
+
+	drop := floor - b.origin
+
+	if drop > 0 {
+		b.buf = b.buf[drop:]
+		b.origin = floor
+	}
+}
+
+// a saved parser position that can later be restored with Reset
+type Mark struct {
+	token int
+	pos int
+	line int
+	column int
+}
+
+// checkpoint the current position so it can be rewound to later
+func (st *ParseState) Mark() Mark {
+	return Mark{
+		token: st.buf.checkpoint(st.Pos),
+		pos: st.Pos,
+		line: st.Line,
+		column: st.Column,
+	}
+}
+
+// rewind the state back to a previously saved mark
+func (st *ParseState) Reset(m Mark) {
+	st.Pos = m.pos
+	st.Line = m.line
+	st.Column = m.column
+
+	st.buf.release(m.token, st.Pos)
+}
+
+// release a mark without rewinding, letting the buffer reclaim old bytes
+func (st *ParseState) commit(m Mark) {
+	st.buf.release(m.token, st.Pos)
+}
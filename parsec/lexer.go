@@ -0,0 +1,291 @@
+package parsec
+
+import "fmt"
+
+// the kind of input a TokenDef recognizes, and the parser that recognizes it
+type TokenDef struct {
+	Name string
+	Match Parser
+}
+
+// a single recognized token, with the source position it started at
+type Token struct {
+	Name string
+	Text string
+	Value interface{}
+	Pos int
+	Line int
+	Column int
+}
+
+// when tokenizing fails, this is why
+type TokenErr struct {
+	Reason string
+	Line int
+	Column int
+}
+
+// get the token error text for a given error
+func (err TokenErr) Error() string {
+	return fmt.Sprintf("%s on line %d, column %d", err.Reason, err.Line, err.Column)
+}
+
+// split source text into a stream of tokens, trying each TokenDef in order
+// at every position and taking the first one that matches
+func Tokenize(source string, defs []TokenDef) ([]Token, error) {
+	matchOne := oneToken(defs)
+
+	p := Bind(Many(matchOne), func(xs interface{}) Parser {
+		return Bind_(Eof, Return(xs))
+	})
+
+	result, err := Parse(source, p)
+
+	if err != nil {
+		return nil, err
+	}
+
+	xs := result.([]interface{})
+	tokens := make([]Token, len(xs))
+
+	for i, x := range xs {
+		tokens[i] = x.(Token)
+	}
+
+	return tokens, nil
+}
+
+// match the first TokenDef that recognizes the input at the current position
+func oneToken(defs []TokenDef) Parser {
+	return func(st *ParseState) (interface{}, error) {
+		pos, line, column := st.Pos, st.Line, st.Column
+
+		for _, def := range defs {
+			m := st.Mark()
+			v, err := def.Match(st)
+
+			// a def that matches zero bytes would tokenize forever; skip it
+			if err != nil || st.Pos == pos {
+				st.Reset(m)
+				continue
+			}
+
+			text := string(st.buf.slice(pos, st.Pos-pos))
+			st.commit(m)
+
+			return Token{
+				Name: def.Name,
+				Text: text,
+				Value: v,
+				Pos: pos,
+				Line: line,
+				Column: column,
+			}, nil
+		}
+
+		return nil, st.trap("No token definition matched")
+	}
+}
+
+// a parser over an already-tokenized stream, rather than raw characters
+type TokenParser func(tokens []Token, pos int) (interface{}, int, error)
+
+// run a TokenParser over a token stream, requiring it to consume every token
+func ParseTokens(tokens []Token, p TokenParser) (interface{}, error) {
+	x, pos, err := p(tokens, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if pos < len(tokens) {
+		t := tokens[pos]
+		return nil, TokenErr{Reason: fmt.Sprintf("Unexpected token '%s'", t.Text), Line: t.Line, Column: t.Column}
+	}
+
+	return x, nil
+}
+
+// bind a TokenParser combinator to a function, return a new combinator
+func TokenBind(p TokenParser, f func(interface{}) TokenParser) TokenParser {
+	return func(tokens []Token, pos int) (interface{}, int, error) {
+		x, pos, err := p(tokens, pos)
+
+		if err != nil {
+			return nil, pos, err
+		}
+
+		return f(x)(tokens, pos)
+	}
+}
+
+// try one TokenParser, if it fails without consuming a token try the next
+func TokenEither(p1, p2 TokenParser) TokenParser {
+	return func(tokens []Token, pos int) (interface{}, int, error) {
+		x, newPos, err := p1(tokens, pos)
+
+		if err == nil {
+			return x, newPos, nil
+		}
+
+		if newPos == pos {
+			return p2(tokens, pos)
+		}
+
+		return nil, newPos, err
+	}
+}
+
+// match any token for which the predicate holds
+func TokenSatisfy(pred func(Token) bool) TokenParser {
+	return func(tokens []Token, pos int) (interface{}, int, error) {
+		if pos >= len(tokens) {
+			return nil, pos, fmt.Errorf("Unexpected end of tokens")
+		}
+
+		t := tokens[pos]
+
+		if pred(t) == false {
+			return nil, pos, TokenErr{Reason: fmt.Sprintf("Unexpected token '%s'", t.Text), Line: t.Line, Column: t.Column}
+		}
+
+		return t, pos + 1, nil
+	}
+}
+
+// match the next token by name, e.g. the Name given to its TokenDef
+func TokenNamed(name string) TokenParser {
+	return TokenSatisfy(func(t Token) bool { return t.Name == name })
+}
+
+// accept any token
+func TokenAny(tokens []Token, pos int) (interface{}, int, error) {
+	return TokenSatisfy(func(t Token) bool { return true })(tokens, pos)
+}
+
+// check for the end of the token stream
+func TokenEof(tokens []Token, pos int) (interface{}, int, error) {
+	if pos >= len(tokens) {
+		return nil, pos, nil
+	}
+
+	t := tokens[pos]
+	return nil, pos, TokenErr{Reason: fmt.Sprintf("Expected end of tokens but got '%s'", t.Text), Line: t.Line, Column: t.Column}
+}
+
+// a lexer binds the whitespace/comment skipping rule its grammar uses to
+// Lexeme/Symbol/Reserved, so two grammars with different skip rules (or
+// concurrent uses of the same grammar) don't share any mutable state
+type Lexer struct {
+	// the parser that recognizes whitespace/comments to be skipped after
+	// every lexeme; must match zero or more repetitions (like Spaces, the
+	// usual choice) - see the note on Lexeme below
+	Skip Parser
+}
+
+// a Lexer that skips plain whitespace, the common case
+var DefaultLexer = Lexer{Skip: Spaces}
+
+// run a parser, then skip whatever lx.Skip matches immediately after it
+//
+// lx.Skip is expected to already match zero or more repetitions (like
+// Spaces, itself a Skip(Space)), so it's run directly rather than wrapped
+// in another Skip - doing that would make it always succeed on no input
+// and spin forever the next time something runs it through Many
+func (lx Lexer) Lexeme(p Parser) Parser {
+	return Bind(p, func(x interface{}) Parser {
+		return Bind_(lx.Skip, Return(x))
+	})
+}
+
+// peek at the next byte without consuming it
+func (st *ParseState) peekByte() (byte, bool) {
+	return st.buf.at(st.Pos)
+}
+
+// true if c could continue an identifier
+func isWordByte(c byte) bool {
+	return c == '_' ||
+		(c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9')
+}
+
+// match a keyword or punctuation symbol; skips trailing whitespace/comments
+// like any other Lexeme. If s ends in a word byte (a keyword like "if"
+// rather than punctuation like "-"), also requires a word boundary after
+// it so Symbol("if") doesn't match the start of "iffy"
+func (lx Lexer) Symbol(s string) Parser {
+	boundary := len(s) > 0 && isWordByte(s[len(s)-1])
+
+	return lx.Lexeme(func(st *ParseState) (interface{}, error) {
+		m := st.Mark()
+		x, err := String(s)(st)
+
+		if err != nil {
+			st.Reset(m)
+			return nil, err
+		}
+
+		if boundary {
+			if c, ok := st.peekByte(); ok && isWordByte(c) {
+				st.Reset(m)
+				return nil, st.trap("Expected '%s' as a whole word", s)
+			}
+		}
+
+		st.commit(m)
+		return x, nil
+	})
+}
+
+// a letter or underscore, then any number of letters, digits, or underscores
+var identifierStart = Either(Letter, Char('_'))
+var identifierPart = Either(AlphaNum, Char('_'))
+
+var rawIdentifier = Bind(identifierStart, func(first interface{}) Parser {
+	return Bind(Many(identifierPart), func(rest interface{}) Parser {
+		return Return(string(first.(byte)) + joinBytes(rest.([]interface{})))
+	})
+})
+
+// join a slice of parsed bytes (as returned by Many) back into a string
+func joinBytes(xs []interface{}) string {
+	bs := make([]byte, len(xs))
+
+	for i, x := range xs {
+		bs[i] = x.(byte)
+	}
+
+	return string(bs)
+}
+
+// an identifier parser that fails if it matches one of a set of reserved
+// words, e.g. keywords that shouldn't be usable as ordinary identifiers
+func (lx Lexer) Reserved(words []string) Parser {
+	keywords := make(map[string]bool, len(words))
+
+	for _, w := range words {
+		keywords[w] = true
+	}
+
+	return lx.Lexeme(func(st *ParseState) (interface{}, error) {
+		m := st.Mark()
+		x, err := rawIdentifier(st)
+
+		if err != nil {
+			st.Reset(m)
+			return nil, err
+		}
+
+		text := x.(string)
+
+		if keywords[text] {
+			st.Reset(m)
+			return nil, st.trap("Unexpected reserved word '%s'", text)
+		}
+
+		st.commit(m)
+		return text, nil
+	})
+}
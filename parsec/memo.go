@@ -0,0 +1,126 @@
+package parsec
+
+import "strings"
+
+// identifies a memoized parser at a given stream position
+type memoKey struct {
+	id int
+	pos int
+}
+
+// a cached outcome of running a memoized parser at some position
+type memoEntry struct {
+	result interface{}
+	err error
+	endPos int
+	endLine int
+	endColumn int
+}
+
+// assigns a stable identity to each Memo-wrapped parser
+var memoIDSeq int
+
+func nextMemoID() int {
+	memoIDSeq++
+	return memoIDSeq
+}
+
+// entry point for packrat parsing: attaches a memo table to the parse
+// state so that every Memo-wrapped parser in p caches its result per
+// position instead of re-parsing on every backtrack
+func ParseMemo(source string, p Parser) (interface{}, error) {
+	st := ParseState{
+		buf: newInputBuffer(strings.NewReader(source)),
+		Line: 1,
+		Column: 1,
+		Pos: 0,
+		memo: make(map[memoKey]*memoEntry),
+	}
+
+	return p(&st)
+}
+
+// memoize a parser so repeated attempts at the same position are served
+// from a cache instead of re-running the parser from scratch
+//
+// this also gives left-recursive grammars a well-defined meaning via the
+// classic "seed-parse, grow the seed" packrat algorithm: the first attempt
+// at a given position installs a failing seed result, so a recursive call
+// back into the same rule at the same position fails immediately rather
+// than looping forever. If that first attempt succeeds, its result becomes
+// the new seed and the rule is re-parsed from the same position - now any
+// left-recursive call sees the better seed and can consume more input
+// before bottoming out. This repeats until a pass fails to grow past the
+// previous seed, at which point the last successful seed is the answer.
+//
+// Memo only has an effect under ParseMemo; elsewhere it's a pass-through,
+// since there's no memo table to consult or grow a seed in.
+func Memo(p Parser) Parser {
+	id := nextMemoID()
+
+	return func(st *ParseState) (interface{}, error) {
+		if st.memo == nil {
+			return p(st)
+		}
+
+		key := memoKey{id: id, pos: st.Pos}
+
+		if entry, ok := st.memo[key]; ok {
+			return restoreMemo(st, entry)
+		}
+
+		startLine, startColumn := st.Line, st.Column
+
+		// seed the table with a failure; a left-recursive re-entry at this
+		// position will hit this entry and fail instead of recursing forever
+		st.memo[key] = &memoEntry{
+			err: st.trap("Left recursion has not yet produced a result"),
+			endPos: key.pos,
+			endLine: startLine,
+			endColumn: startColumn,
+		}
+
+		grown := false
+
+		for {
+			st.Pos, st.Line, st.Column = key.pos, startLine, startColumn
+
+			result, err := p(st)
+			prev := st.memo[key]
+
+			// once we have a real result, stop as soon as a pass fails or
+			// fails to consume more than the best result seen so far; but
+			// the very first real pass always replaces the bootstrap seed,
+			// even if it failed, so a genuine parse failure isn't masked
+			// by the seed's "left recursion" error
+			if grown && (err != nil || st.Pos <= prev.endPos) {
+				break
+			}
+
+			st.memo[key] = &memoEntry{
+				result: result,
+				err: err,
+				endPos: st.Pos,
+				endLine: st.Line,
+				endColumn: st.Column,
+			}
+
+			if err != nil {
+				break
+			}
+
+			grown = true
+		}
+
+		return restoreMemo(st, st.memo[key])
+	}
+}
+
+// restore the parse state to where a memoized entry left off and return it
+func restoreMemo(st *ParseState, entry *memoEntry) (interface{}, error) {
+	st.Pos = entry.endPos
+	st.Line = entry.endLine
+	st.Column = entry.endColumn
+
+	return entry.result, entry.err
+}
@@ -3,6 +3,8 @@ package parsec
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"strings"
 )
 
 // parse combinator function
@@ -27,27 +29,44 @@ var Eol = Either(Eof, Newline)
 
 // the current parser state
 type ParseState struct {
-	Source string
+	buf *inputBuffer
 	Pos int
 	Line int
+	Column int
+	Filename string
+	memo map[memoKey]*memoEntry
 }
 
 // when parsing fails, this is why
 type ParseErr struct {
 	Reason string
 	Line int
+	Column int
+	Pos int
+	Filename string
+	Expected []string
 }
 
 // get the parse error text for a given error
 func (err ParseErr) Error() string {
-	return fmt.Sprintf("%s on line %d", err.Reason, err.Line)
+	if err.Filename != "" {
+		return fmt.Sprintf("%s:%d:%d: %s", err.Filename, err.Line, err.Column, err.Reason)
+	}
+
+	return fmt.Sprintf("%s on line %d, column %d", err.Reason, err.Line, err.Column)
 }
 
 // entry point for parsing data
 func Parse(source string, p Parser) (interface{}, error) {
+	return ParseNamed("", source, p)
+}
+
+// entry point for parsing data from a streaming source
+func ParseReader(r io.Reader, p Parser) (interface{}, error) {
 	st := ParseState{
-		Source: source,
+		buf: newInputBuffer(r),
 		Line: 1,
+		Column: 1,
 		Pos: 0,
 	}
 
@@ -55,34 +74,63 @@ func Parse(source string, p Parser) (interface{}, error) {
 	return p(&st)
 }
 
+// entry point for parsing data, attributing errors to a filename
+func ParseNamed(filename, source string, p Parser) (interface{}, error) {
+	st := ParseState{
+		buf: newInputBuffer(strings.NewReader(source)),
+		Line: 1,
+		Column: 1,
+		Pos: 0,
+		Filename: filename,
+	}
+
+	// call the parse function with the state
+	return p(&st)
+}
+
 // get the next character in the parse stream
 func (st *ParseState) next(pred func(byte) bool) (byte, bool) {
-	if st.Pos < len(st.Source) {
-		c := st.Source[st.Pos]
+	c, ok := st.buf.at(st.Pos)
 
-		// make sure the predicate matches
-		if pred(c) == false {
-			return c, false
-		}
+	if ok == false {
+		// just a null character and failure
+		return '\000', false
+	}
 
-		// advance the stream position
-		st.Pos++
+	// make sure the predicate matches
+	if pred(c) == false {
+		return c, false
+	}
 
-		// advance to another line?
-		if c == '\n' {
-			st.Line++
-		}
+	// advance the stream position
+	st.Pos++
 
-		return c, true
+	// advance to another line?
+	if c == '\n' {
+		st.Line++
+		st.Column = 1
+	} else {
+		st.Column++
 	}
 
-	// just a null character and failure
-	return '\000', false
+	return c, true
 }
 
 // generate a formated parse error
 func (st *ParseState) trap(format string, args... interface{}) ParseErr {
-	return ParseErr{Line: st.Line, Reason: fmt.Sprintf(format, args...)}
+	return st.expect(nil, format, args...)
+}
+
+// generate a formated parse error along with the set of things that were expected
+func (st *ParseState) expect(expected []string, format string, args... interface{}) ParseErr {
+	return ParseErr{
+		Line: st.Line,
+		Column: st.Column,
+		Pos: st.Pos,
+		Filename: st.Filename,
+		Expected: expected,
+		Reason: fmt.Sprintf(format, args...),
+	}
 }
 
 // bind a parse combinator to a function, return a new combinator
@@ -130,40 +178,58 @@ func Fail(msg string) Parser {
 // try one parser, if it fails (without consuming input) try the next
 func Either(p1, p2 Parser) Parser {
 	return func(st *ParseState) (interface{}, error) {
-		oldPos := st.Pos
+		m := st.Mark()
 
 		// try the first parser
-		x, err := p1(st)
+		x, err1 := p1(st)
 
 		// success?
-		if err == nil {
+		if err1 == nil {
+			st.commit(m)
 			return x, nil
 		}
 
 		// make sure no input was consumed
-		if st.Pos == oldPos {
-			return p2(st)
+		if st.Pos != m.pos {
+			st.commit(m)
+			return nil, err1
 		}
 
-		return nil, err
+		st.Reset(m)
+
+		// try the second parser
+		y, err2 := p2(st)
+
+		// success?
+		if err2 == nil {
+			return y, nil
+		}
+
+		// if both branches failed without consuming input, merge what they expected
+		if st.Pos == m.pos {
+			return nil, mergeParseErr(err1, err2)
+		}
+
+		return nil, err2
 	}
 }
 
 // attempt to match a parser, if it fails pretend no input was consumed
 func Try(p Parser) Parser {
 	return func(st *ParseState) (interface{}, error) {
-		oldPos := st.Pos
+		m := st.Mark()
 
 		// try the first parser
 		x, err := p(st)
 
 		// on success, return the value
 		if err == nil {
+			st.commit(m)
 			return x, nil
 		}
 
 		// reset back to the original position
-		st.Pos = oldPos
+		st.Reset(m)
 
 		// this result value should be ignored
 		return nil, err
@@ -186,7 +252,7 @@ func Eof(st *ParseState) (interface{}, error) {
 	c, ok := st.next(func(x byte) bool { return true })
 
 	if ok {
-		return nil, st.trap("Expected end of file but got '%c'", c)
+		return nil, st.expect([]string{"end of file"}, "Expected end of file but got '%c'", c)
 	}
 
 	return nil, nil
@@ -194,6 +260,8 @@ func Eof(st *ParseState) (interface{}, error) {
 
 // check for the next character being a specific one
 func Char(c byte) Parser {
+	expected := []string{fmt.Sprintf("'%c'", c)}
+
 	return func(st *ParseState) (interface{}, error) {
 		x, ok := st.next(func(b byte) bool { return b == c })
 
@@ -201,12 +269,14 @@ func Char(c byte) Parser {
 			return x, nil
 		}
 
-		return nil, st.trap("Expected '%c'", c)
+		return nil, st.expect(expected, "Expected '%c'", c)
 	}
 }
 
 // check for the next character being from a set
 func OneOf(set []byte) Parser {
+	expected := []string{fmt.Sprintf("one of '%s'", string(set))}
+
 	return func(st *ParseState) (interface{}, error) {
 		x, ok := st.next(func(c byte) bool { return bytes.IndexByte(set, c) >= 0 })
 
@@ -214,7 +284,7 @@ func OneOf(set []byte) Parser {
 			return x, nil
 		}
 
-		return nil, st.trap("Expected one of '%s' but got '%c'", string(set), x)
+		return nil, st.expect(expected, "Expected one of '%s' but got '%c'", string(set), x)
 	}
 }
 
@@ -233,21 +303,24 @@ func NoneOf(set []byte) Parser {
 
 // match an exact string, don't consume input if a match fails
 func String(s string) Parser {
+	expected := []string{fmt.Sprintf("'%s'", s)}
+
 	return func(st *ParseState) (interface{}, error) {
-		oldPos := st.Pos
+		m := st.Mark()
 
 		// try and match each character
 		for _, c := range []byte(s) {
 			_, ok := st.next(func(b byte) bool { return b == c })
 
 			if ok == false {
-				st.Pos = oldPos
+				st.Reset(m)
 
 				// the string failed to match
-				return nil, st.trap("Expected '%s'", s)
+				return nil, st.expect(expected, "Expected '%s'", s)
 			}
 		}
 
+		st.commit(m)
 		return s, nil
 	}
 }
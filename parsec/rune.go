@@ -0,0 +1,134 @@
+package parsec
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// common rune-oriented parser combinators
+var AnyLetter = Satisfy("a letter", unicode.IsLetter)
+var AnyDigit = Satisfy("a digit", unicode.IsDigit)
+
+// get the next rune in the parse stream, decoding UTF-8 as necessary
+func (st *ParseState) nextRune(pred func(rune) bool) (rune, bool) {
+	chunk := st.buf.slice(st.Pos, utf8.UTFMax)
+
+	if len(chunk) == 0 {
+		return utf8.RuneError, false
+	}
+
+	// decode the next rune and its width in bytes
+	r, size := utf8.DecodeRune(chunk)
+
+	// make sure the predicate matches
+	if pred(r) == false {
+		return r, false
+	}
+
+	// advance the stream position by the rune's byte width
+	st.Pos += size
+
+	// advance to another line?
+	if r == '\n' {
+		st.Line++
+		st.Column = 1
+	} else {
+		st.Column++
+	}
+
+	return r, true
+}
+
+// accept any valid rune
+func AnyRune(st *ParseState) (interface{}, error) {
+	r, ok := st.nextRune(func(x rune) bool { return true })
+
+	if ok {
+		return r, nil
+	}
+
+	return nil, st.expect([]string{"any character"}, "Unexpected end of file")
+}
+
+// check for the next rune being a specific one
+func Rune(r rune) Parser {
+	expected := []string{fmt.Sprintf("'%c'", r)}
+
+	return func(st *ParseState) (interface{}, error) {
+		x, ok := st.nextRune(func(c rune) bool { return c == r })
+
+		if ok {
+			return x, nil
+		}
+
+		return nil, st.expect(expected, "Expected '%c'", r)
+	}
+}
+
+// check for the next rune being from a set
+func RuneOneOf(set []rune) Parser {
+	expected := []string{fmt.Sprintf("one of '%s'", string(set))}
+
+	return func(st *ParseState) (interface{}, error) {
+		x, ok := st.nextRune(func(c rune) bool { return runeIndex(set, c) >= 0 })
+
+		if ok {
+			return x, nil
+		}
+
+		return nil, st.expect(expected, "Expected one of '%s' but got '%c'", string(set), x)
+	}
+}
+
+// check for the next rune not being from a set
+func RuneNoneOf(set []rune) Parser {
+	expected := []string{fmt.Sprintf("none of '%s'", string(set))}
+
+	return func(st *ParseState) (interface{}, error) {
+		x, ok := st.nextRune(func(c rune) bool { return runeIndex(set, c) < 0 })
+
+		if ok {
+			return x, nil
+		}
+
+		return nil, st.expect(expected, "Unexpected '%c'", x)
+	}
+}
+
+// match any rune for which the predicate holds true; label describes what
+// was expected (e.g. "a letter"), so failures can participate in Either's
+// expected-set merging the same way the byte combinators do
+func Satisfy(label string, pred func(rune) bool) Parser {
+	expected := []string{label}
+
+	return func(st *ParseState) (interface{}, error) {
+		x, ok := st.nextRune(pred)
+
+		if ok {
+			return x, nil
+		}
+
+		return nil, st.expect(expected, "Expected %s but got %s", label, runeDesc(x))
+	}
+}
+
+// find the index of a rune within a set, or -1 if it isn't present
+func runeIndex(set []rune, r rune) int {
+	for i, c := range set {
+		if c == r {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// describe a rune for an error message
+func runeDesc(r rune) string {
+	if r == utf8.RuneError {
+		return "invalid rune"
+	}
+
+	return fmt.Sprintf("'%c'", r)
+}